@@ -1,18 +1,28 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/urfave/cli/v2"
 
+	"github.com/iawia002/lux/archive"
 	"github.com/iawia002/lux/downloader"
+	"github.com/iawia002/lux/downloader/resume"
 	"github.com/iawia002/lux/extractors"
+	"github.com/iawia002/lux/extractors/external"
+	"github.com/iawia002/lux/livestream"
+	"github.com/iawia002/lux/postprocess"
+	"github.com/iawia002/lux/progress"
 	"github.com/iawia002/lux/request"
 	"github.com/iawia002/lux/utils"
 )
@@ -23,6 +33,36 @@ const (
 	version = "v0.19.0"
 )
 
+// jsonOutputMu serializes "--json" output across concurrent download
+// workers so their encoded objects don't interleave on stdout.
+var jsonOutputMu sync.Mutex
+
+// externalExtractorOnce builds a single Extractor (and therefore a single
+// shared ratelimit.Limiter) the first time it's needed, so repeated
+// invocations across a playlist all throttle against the same token bucket
+// instead of each download() call getting its own fresh, already-full one.
+var (
+	externalExtractorOnce sync.Once
+	externalExtractorInst *external.Extractor
+)
+
+// externalExtractor returns the shared external-extractor instance for this
+// run, or nil if --external-extractor wasn't set.
+func externalExtractor(c *cli.Context) *external.Extractor {
+	binary := c.String("external-extractor")
+	if binary == "" {
+		return nil
+	}
+	externalExtractorOnce.Do(func() {
+		externalExtractorInst = external.New(external.Options{
+			Binary:      binary,
+			Args:        c.StringSlice("external-args"),
+			MinInterval: time.Second,
+		})
+	})
+	return externalExtractorInst
+}
+
 func init() {
 	cli.VersionPrinter = func(c *cli.Context) {
 		blue := color.New(color.FgBlue)
@@ -197,6 +237,108 @@ func New() *cli.App {
 				Aliases: []string{"eto"},
 				Usage:   "File name of each bilibili episode doesn't include the playlist title",
 			},
+
+			// live
+			&cli.BoolFlag{
+				Name:  "live",
+				Usage: "Record a live HLS/DASH stream instead of downloading a single file",
+			},
+			&cli.StringFlag{
+				Name:  "live-quality",
+				Usage: "Preferred live quality: \"best\", a height such as \"1080p\", or an exact variant bandwidth",
+			},
+			&cli.DurationFlag{
+				Name:  "live-max-duration",
+				Usage: "Stop recording after this long, even if the stream is still live (0 means unlimited)",
+			},
+			&cli.StringFlag{
+				Name:  "live-filename",
+				Usage: "Go template for the recorded file name, e.g. \"{{.Site}}-{{.Title}}\"",
+			},
+
+			// sync
+			&cli.StringFlag{
+				Name:  "archive",
+				Usage: "Record downloaded videos in this file and skip them on later runs",
+			},
+			&cli.StringFlag{
+				Name:  "sync-since",
+				Usage: "Only sync videos published on or after this date (YYYY-MM-DD)",
+			},
+			&cli.UintFlag{
+				Name:  "max-videos",
+				Usage: "Stop after downloading this many new videos, 0 means unlimited",
+			},
+
+			// external extractor
+			&cli.StringFlag{
+				Name:  "external-extractor",
+				Usage: "Path to an external tool (e.g. yt-dlp) used as a fallback for sites lux doesn't support",
+			},
+			&cli.StringSliceFlag{
+				Name:  "external-args",
+				Usage: "Extra arguments passed to --external-extractor before the URL",
+			},
+
+			&cli.BoolFlag{
+				Name:  "resume",
+				Value: true,
+				Usage: "Resume an interrupted multi-thread download from its .lux-part sidecar",
+			},
+			&cli.BoolFlag{
+				Name:  "no-resume",
+				Usage: "Ignore any .lux-part sidecar and restart the download from scratch",
+			},
+
+			// postprocess
+			&cli.StringFlag{
+				Name:  "merge-output-format",
+				Usage: "Container used to mux separate audio/video streams: mp4, mkv or webm",
+			},
+			&cli.StringFlag{
+				Name:  "recode",
+				Usage: "Re-encode the final file into this container: mp4 or mkv",
+			},
+			&cli.BoolFlag{
+				Name:  "embed-thumbnail",
+				Usage: "Embed the downloaded thumbnail into the output file",
+			},
+			&cli.BoolFlag{
+				Name:  "embed-subs",
+				Usage: "Embed downloaded captions as soft subtitles",
+			},
+			&cli.BoolFlag{
+				Name:  "burn-subs",
+				Usage: "Burn downloaded captions into the video instead of embedding them as soft subs",
+			},
+			&cli.BoolFlag{
+				Name:  "embed-metadata",
+				Usage: "Embed title, site name and source URL metadata into the output file",
+			},
+			&cli.BoolFlag{
+				Name:  "write-info-json",
+				Usage: "Write a .info.json sidecar with the extracted data, for archival",
+			},
+			&cli.BoolFlag{
+				Name:  "keep-parts",
+				Usage: "Keep the original part files after merging/recoding",
+			},
+			&cli.StringFlag{
+				Name:  "ffmpeg-location",
+				Usage: "Path to the ffmpeg binary used for post-processing",
+			},
+
+			// scheduler
+			&cli.UintFlag{
+				Name:  "concurrent-downloads",
+				Value: 1,
+				Usage: "How many URLs to extract and download at once",
+			},
+			&cli.StringFlag{
+				Name:  "progress",
+				Value: "plain",
+				Usage: "Progress output format: bar, json, plain or tui",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			args := c.Args().Slice()
@@ -242,19 +384,7 @@ func New() *cli.App {
 				Silent:     c.Bool("silent"),
 			})
 
-			var isErr bool
-			for _, videoURL := range args {
-				if err := download(c, videoURL); err != nil {
-					fmt.Fprintf(
-						color.Output,
-						"Downloading %s error:\n",
-						color.CyanString("%s", videoURL),
-					)
-					fmt.Printf("%+v\n", err)
-					isErr = true
-				}
-			}
-			if isErr {
+			if downloadAll(c, args) {
 				return cli.Exit("", 1)
 			}
 			return nil
@@ -266,7 +396,53 @@ func New() *cli.App {
 	return app
 }
 
-func download(c *cli.Context, videoURL string) error {
+// downloadAll runs download for every URL in args, using "concurrent-downloads"
+// workers, and reports progress through the reporter selected by
+// "--progress". It returns true if any URL failed.
+func downloadAll(c *cli.Context, args []string) bool {
+	concurrency := int(c.Uint("concurrent-downloads"))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	reporter := progress.New(c.String("progress"), color.Output)
+	defer reporter.Close()
+
+	urls := make(chan string)
+	go func() {
+		defer close(urls)
+		for _, videoURL := range args {
+			urls <- videoURL
+		}
+	}()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		isErr bool
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for videoURL := range urls {
+				reporter.Report(progress.Event{URL: videoURL, Stage: progress.StageExtracting})
+				if err := download(c, videoURL, reporter); err != nil {
+					reporter.Report(progress.Event{URL: videoURL, Stage: progress.StageError, Err: err.Error()})
+					mu.Lock()
+					isErr = true
+					mu.Unlock()
+					continue
+				}
+				reporter.Report(progress.Event{URL: videoURL, Stage: progress.StageDone})
+			}
+		}()
+	}
+	wg.Wait()
+
+	return isErr
+}
+
+func download(c *cli.Context, videoURL string, reporter progress.Reporter) error {
 	data, err := extractors.Extract(videoURL, extractors.Options{
 		Playlist:         c.Bool("playlist"),
 		Items:            c.String("items"),
@@ -282,10 +458,32 @@ func download(c *cli.Context, videoURL string) error {
 	if err != nil {
 		// if this error occurs, it means that an error occurred before actually starting to extract data
 		// (there is an error in the preparation step), and the data list is empty.
-		return err
+		if ext := externalExtractor(c); ext != nil {
+			data, err = ext.Extract(videoURL)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var arc *archive.Archive
+	if archivePath := c.String("archive"); archivePath != "" {
+		arc, err = archive.Open(archivePath)
+		if err != nil {
+			return err
+		}
+		data, err = applySync(c, arc, data)
+		if err != nil {
+			return err
+		}
 	}
 
 	if c.Bool("json") {
+		// Guards os.Stdout against interleaved writes from concurrent
+		// --concurrent-downloads workers.
+		jsonOutputMu.Lock()
+		defer jsonOutputMu.Unlock()
+
 		e := json.NewEncoder(os.Stdout)
 		e.SetIndent("", "\t")
 		e.SetEscapeHTML(false)
@@ -296,6 +494,10 @@ func download(c *cli.Context, videoURL string) error {
 		return nil
 	}
 
+	if c.Bool("live") {
+		return recordLive(c, data)
+	}
+
 	defaultDownloader := downloader.New(downloader.Options{
 		Silent:         c.Bool("silent"),
 		InfoOnly:       c.Bool("info"),
@@ -322,8 +524,29 @@ func download(c *cli.Context, videoURL string) error {
 			errors = append(errors, item.Err)
 			continue
 		}
-		if err = defaultDownloader.Download(item); err != nil {
+		handled, err := tryResumeDownload(c, item, videoURL, reporter)
+		if !handled {
+			err = defaultDownloader.Download(item)
+		}
+		if err != nil {
 			errors = append(errors, err)
+			continue
+		}
+		if needsPostprocess(c) {
+			if _, err := runPostprocess(c, item); err != nil {
+				errors = append(errors, err)
+				continue
+			}
+		}
+		if arc != nil {
+			arc.Add(archive.Entry{
+				Extractor: item.Site,
+				SiteID:    item.SiteID,
+				Title:     item.Title,
+			})
+			if err := arc.Save(); err != nil {
+				errors = append(errors, err)
+			}
 		}
 	}
 	if len(errors) != 0 {
@@ -331,3 +554,253 @@ func download(c *cli.Context, videoURL string) error {
 	}
 	return nil
 }
+
+// applySync drops items already recorded in arc, optionally filters out
+// anything published before c's "sync-since" date, sorts the remainder by
+// publish date ascending, and caps the result at "max-videos" new items —
+// mirroring the ytsync "SyncedVideos" skip-what-we-have pattern.
+func applySync(c *cli.Context, arc *archive.Archive, data []*extractors.Data) ([]*extractors.Data, error) {
+	var since time.Time
+	if s := c.String("sync-since"); s != "" {
+		var err error
+		since, err = time.Parse("2006-01-02", s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --sync-since %q: %w", s, err)
+		}
+	}
+
+	// Items that failed extraction are reported as errors further down the
+	// pipeline, but they aren't real videos, so they shouldn't compete with
+	// ones that are for the --max-videos budget: a run with 5 failed items
+	// and 3 good new videos and --max-videos 3 should still download all 3
+	// good ones, not get crowded out by the failures.
+	var errItems []*extractors.Data
+	filtered := make([]*extractors.Data, 0, len(data))
+	for _, item := range data {
+		if item.Err != nil {
+			errItems = append(errItems, item)
+			continue
+		}
+		if arc.Has(item.Site, item.SiteID) {
+			continue
+		}
+		if !since.IsZero() && item.PublishedAt.Before(since) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].PublishedAt.Before(filtered[j].PublishedAt)
+	})
+
+	if max := c.Uint("max-videos"); max > 0 && uint(len(filtered)) > max {
+		filtered = filtered[:max]
+	}
+	return append(errItems, filtered...), nil
+}
+
+// recordLive records each live stream in data to disk instead of handing it
+// to the regular downloader, which assumes a finite, seekable file.
+func recordLive(c *cli.Context, data []*extractors.Data) error {
+	recorder := livestream.New(livestream.Options{
+		Quality:     c.String("live-quality"),
+		MaxDuration: c.Duration("live-max-duration"),
+		Filename:    c.String("live-filename"),
+		OutputPath:  c.String("output-path"),
+	})
+
+	var errs []error
+	for _, item := range data {
+		if item.Err != nil {
+			errs = append(errs, item.Err)
+			continue
+		}
+
+		manifestURL, err := liveManifestURL(item, c.String("stream-format"))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		_, err = recorder.Record(context.Background(), manifestURL, livestream.Info{
+			Title: item.Title,
+			Site:  item.Site,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recording %s: %w", item.Title, err))
+		}
+	}
+	if len(errs) != 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// tryResumeDownload downloads item through the resumable range-request path
+// instead of defaultDownloader when --resume is enabled and the request is
+// one it can actually handle: a single-part, plain HTTP stream with a
+// deterministic output path (from --output-name). It reports handled=false
+// for anything else, warning on stderr about why resume didn't apply instead
+// of silently falling back, since --resume defaults to true and a user
+// relying on it to survive a flaky connection should know it isn't active.
+//
+// While it runs, it reports real StageDownloading progress (bytes/total/ETA)
+// to reporter as chunks land — the one download path in this tree that can,
+// since defaultDownloader.Download is opaque to this package.
+func tryResumeDownload(c *cli.Context, item *extractors.Data, videoURL string, reporter progress.Reporter) (handled bool, err error) {
+	if !c.Bool("resume") || c.Bool("no-resume") {
+		return false, nil
+	}
+
+	if !c.Bool("multi-thread") {
+		fmt.Fprintf(color.Error, "%s: resume unavailable, falling back to a non-resumable download (pass --multi-thread to use the resumable range-request downloader)\n", item.Title)
+		return false, nil
+	}
+
+	_, outputPath, ok := resolvedPartPaths(c, item)
+	if !ok {
+		fmt.Fprintf(color.Error, "%s: resume unavailable, falling back to a non-resumable download (pass --output-name to pin a deterministic file name the resumable downloader can journal against)\n", item.Title)
+		return false, nil
+	}
+
+	stream := pickStream(item, c.String("stream-format"))
+	if stream == nil || len(stream.Parts) != 1 {
+		fmt.Fprintf(color.Error, "%s: resume unavailable, falling back to a non-resumable download (stream has multiple parts to merge)\n", item.Title)
+		return false, nil
+	}
+
+	url := stream.Parts[0].URL
+	if livestream.IsManifest(url) {
+		fmt.Fprintf(color.Error, "%s: resume unavailable, falling back to a non-resumable download (live manifest, not a plain HTTP stream)\n", item.Title)
+		return false, nil
+	}
+
+	start := time.Now()
+	rd := resume.New(resume.Options{
+		ThreadNumber: int(c.Uint("thread")),
+		ChunkSizeMB:  int(c.Uint("chunk-size")),
+		UserAgent:    c.String("user-agent"),
+		Refer:        c.String("refer"),
+		OnProgress: func(bytesDone, totalSize int64) {
+			var eta time.Duration
+			if elapsed := time.Since(start); bytesDone > 0 && elapsed > 0 {
+				eta = time.Duration(float64(elapsed) * float64(totalSize-bytesDone) / float64(bytesDone))
+			}
+			reporter.Report(progress.Event{
+				URL:    videoURL,
+				Stage:  progress.StageDownloading,
+				Format: c.String("stream-format"),
+				Bytes:  bytesDone,
+				Total:  totalSize,
+				ETA:    eta,
+			})
+		},
+	})
+	return true, rd.Download(url, outputPath)
+}
+
+// pickStream returns item's stream named format, falling back to an
+// arbitrary stream when format is empty or unknown — the same "whatever we
+// extracted" fallback downloader.New's own Stream option resolves to.
+func pickStream(item *extractors.Data, format string) *extractors.Stream {
+	if stream, ok := item.Streams[format]; ok {
+		return stream
+	}
+	for _, s := range item.Streams {
+		return s
+	}
+	return nil
+}
+
+// liveManifestURL picks the manifest URL to record from item, preferring the
+// stream named format when given, and returns an error if the chosen stream
+// isn't an HLS/DASH manifest at all.
+func liveManifestURL(item *extractors.Data, format string) (string, error) {
+	stream := pickStream(item, format)
+	if stream == nil || len(stream.Parts) == 0 {
+		return "", fmt.Errorf("%s: no stream available to record", item.Title)
+	}
+
+	manifestURL := stream.Parts[0].URL
+	if !livestream.IsManifest(manifestURL) {
+		return "", fmt.Errorf("%s: stream is not a live HLS/DASH manifest", item.Title)
+	}
+	return manifestURL, nil
+}
+
+// needsPostprocess reports whether any post-processing flag was set, so a
+// plain download doesn't pay for an extra ffmpeg lookup.
+func needsPostprocess(c *cli.Context) bool {
+	return c.String("merge-output-format") != "" ||
+		c.String("recode") != "" ||
+		c.Bool("embed-thumbnail") ||
+		c.Bool("embed-subs") ||
+		c.Bool("embed-metadata") ||
+		c.Bool("write-info-json")
+}
+
+// resolvedPartPaths derives the file paths defaultDownloader.Download just
+// wrote for item, so post-processing can locate them without the downloader
+// itself reporting them back. This only works when --output-name pins the
+// file name lux used; without it, the downloader's own (unexported) title
+// sanitization decides the name and post-processing has nothing reliable to
+// open.
+//
+// When item's chosen stream has more than one part, lux downloads each part
+// separately (e.g. a DASH video-only and audio-only pair) before merging
+// them, so partPaths has one entry per part; otherwise it has exactly one,
+// the final output path itself.
+func resolvedPartPaths(c *cli.Context, item *extractors.Data) (partPaths []string, outputPath string, ok bool) {
+	name := c.String("output-name")
+	if name == "" {
+		return nil, "", false
+	}
+	stream := pickStream(item, c.String("stream-format"))
+	if stream == nil || len(stream.Parts) == 0 {
+		return nil, "", false
+	}
+
+	ext := stream.Parts[0].Ext
+	if ext == "" {
+		ext = "mp4"
+	}
+	dir := c.String("output-path")
+	outputPath = filepath.Join(dir, name+"."+strings.TrimPrefix(ext, "."))
+
+	if len(stream.Parts) == 1 {
+		return []string{outputPath}, outputPath, true
+	}
+
+	partPaths = make([]string, len(stream.Parts))
+	for i, part := range stream.Parts {
+		partExt := part.Ext
+		if partExt == "" {
+			partExt = ext
+		}
+		partPaths[i] = filepath.Join(dir, fmt.Sprintf("%s.part%d.%s", name, i, strings.TrimPrefix(partExt, ".")))
+	}
+	return partPaths, outputPath, true
+}
+
+// runPostprocess runs the configured ffmpeg post-processing steps over the
+// file(s) defaultDownloader.Download just produced for item.
+func runPostprocess(c *cli.Context, item *extractors.Data) (string, error) {
+	partPaths, outputPath, ok := resolvedPartPaths(c, item)
+	if !ok {
+		return "", fmt.Errorf("%s: post-processing requires --output-name to locate the downloaded file", item.Title)
+	}
+
+	processor := postprocess.New(postprocess.Options{
+		FFmpegPath:        c.String("ffmpeg-location"),
+		MergeOutputFormat: c.String("merge-output-format"),
+		Recode:            c.String("recode"),
+		EmbedThumbnail:    c.Bool("embed-thumbnail"),
+		EmbedSubs:         c.Bool("embed-subs"),
+		BurnSubs:          c.Bool("burn-subs"),
+		EmbedMetadata:     c.Bool("embed-metadata"),
+		WriteInfoJSON:     c.Bool("write-info-json"),
+		KeepParts:         c.Bool("keep-parts"),
+	})
+	return processor.Run(context.Background(), item, partPaths, outputPath)
+}
@@ -0,0 +1,110 @@
+// Package archive persists the set of successfully downloaded videos to a
+// JSON file so that repeated runs against the same channel or playlist skip
+// items that were already fetched.
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry records one successfully downloaded item.
+type Entry struct {
+	Extractor    string    `json:"extractor"`
+	SiteID       string    `json:"site_id"`
+	Title        string    `json:"title"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// key uniquely identifies an item across runs.
+func key(extractor, siteID string) string {
+	return extractor + ":" + siteID
+}
+
+// Archive is a persisted "already downloaded" set, keyed by extractor+site-ID.
+// It is safe for concurrent use, since a single run may have several
+// downloads in flight at once (see --concurrent-downloads).
+type Archive struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// opened caches the Archive for each path, so concurrent workers downloading
+// against the same --archive file share one in-memory set instead of each
+// reading and overwriting the file independently.
+var (
+	openedMu sync.Mutex
+	opened   = make(map[string]*Archive)
+)
+
+// Open loads path if it exists, or returns an empty Archive backed by path
+// if it doesn't. A missing file is not an error: the first Save creates it.
+// Calling Open again for the same path returns the same Archive instance.
+func Open(path string) (*Archive, error) {
+	openedMu.Lock()
+	defer openedMu.Unlock()
+
+	if a, ok := opened[path]; ok {
+		return a, nil
+	}
+
+	a := &Archive{
+		path:    path,
+		entries: make(map[string]Entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			opened[path] = a
+			return a, nil
+		}
+		return nil, fmt.Errorf("archive: reading %s: %w", path, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &a.entries); err != nil {
+			return nil, fmt.Errorf("archive: parsing %s: %w", path, err)
+		}
+	}
+	opened[path] = a
+	return a, nil
+}
+
+// Has reports whether extractor+siteID was already downloaded.
+func (a *Archive) Has(extractor, siteID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.entries[key(extractor, siteID)]
+	return ok
+}
+
+// Add records extractor+siteID as downloaded. The caller must call Save to
+// persist it; Add only updates the in-memory set.
+func (a *Archive) Add(entry Entry) {
+	entry.DownloadedAt = time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries[key(entry.Extractor, entry.SiteID)] = entry
+}
+
+// Save writes the archive back to disk. It should be called after every
+// successful download rather than once at the end, so that a run interrupted
+// partway through still records what it finished.
+func (a *Archive) Save() error {
+	a.mu.Lock()
+	data, err := json.MarshalIndent(a.entries, "", "\t")
+	a.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("archive: encoding: %w", err)
+	}
+	if err := os.WriteFile(a.path, data, 0o644); err != nil {
+		return fmt.Errorf("archive: writing %s: %w", a.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenMissingFileIsNotAnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.json")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() = %v, want nil error for a missing file", err)
+	}
+	if a.Has("youtube", "abc") {
+		t.Error("Has() = true on a fresh archive, want false")
+	}
+}
+
+func TestAddAndHas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.json")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() = %v", err)
+	}
+
+	a.Add(Entry{Extractor: "youtube", SiteID: "abc", Title: "clip"})
+
+	if !a.Has("youtube", "abc") {
+		t.Error("Has() = false after Add(), want true")
+	}
+	if a.Has("youtube", "other") {
+		t.Error("Has() = true for an entry never added, want false")
+	}
+}
+
+func TestSaveAndReopenRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.json")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() = %v", err)
+	}
+	a.Add(Entry{Extractor: "bilibili", SiteID: "xyz", Title: "video"})
+	if err := a.Save(); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	// Open caches by path within a process, so read the file back directly
+	// through a fresh cache entry at a distinct (but identical-content) path
+	// to exercise the on-disk format rather than the in-memory cache.
+	copyPath := filepath.Join(t.TempDir(), "archive.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading saved archive: %v", err)
+	}
+	if err := os.WriteFile(copyPath, data, 0o644); err != nil {
+		t.Fatalf("writing archive copy: %v", err)
+	}
+
+	reopened, err := Open(copyPath)
+	if err != nil {
+		t.Fatalf("Open() on reload = %v", err)
+	}
+	if !reopened.Has("bilibili", "xyz") {
+		t.Error("Has() = false after reload, want true")
+	}
+}
+
+func TestOpenReturnsSameInstanceForSamePath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.json")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() = %v", err)
+	}
+	a.Add(Entry{Extractor: "youtube", SiteID: "abc", Title: "clip"})
+
+	b, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() = %v", err)
+	}
+	if !b.Has("youtube", "abc") {
+		t.Error("second Open() for the same path returned a distinct archive, want the cached instance")
+	}
+}
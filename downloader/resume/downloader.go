@@ -0,0 +1,225 @@
+package resume
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Options configures a resumable single-URL download.
+type Options struct {
+	// ThreadNumber bounds how many byte ranges are fetched concurrently.
+	ThreadNumber int
+	// ChunkSizeMB splits each missing range into chunks of this size, so a
+	// dropped connection only has to re-fetch one chunk, not the whole
+	// missing range.
+	ChunkSizeMB int
+	UserAgent   string
+	Refer       string
+	// OnProgress, if set, is called after each chunk lands with the total
+	// bytes done so far and the resource's total size.
+	OnProgress func(bytesDone, totalSize int64)
+}
+
+// Downloader fetches a single URL to outputPath over HTTP range requests,
+// journaling each completed chunk to outputPath's .lux-part sidecar so a
+// dropped connection resumes instead of restarting from zero.
+type Downloader struct {
+	options Options
+	client  *http.Client
+}
+
+// New returns a Downloader configured with options.
+func New(options Options) *Downloader {
+	if options.ThreadNumber <= 0 {
+		options.ThreadNumber = 1
+	}
+	if options.ChunkSizeMB <= 0 {
+		options.ChunkSizeMB = 1
+	}
+	return &Downloader{
+		options: options,
+		client:  &http.Client{},
+	}
+}
+
+// Download fetches url to outputPath, resuming from outputPath's sidecar
+// when the resource is unchanged, and removes the sidecar on success.
+func (d *Downloader) Download(url, outputPath string) error {
+	head, err := d.head(url)
+	if err != nil {
+		return fmt.Errorf("resume: HEAD %s: %w", url, err)
+	}
+
+	state, err := Load(outputPath)
+	if err != nil {
+		return err
+	}
+	if state == nil || !state.Matches(url, head.etag, head.lastModified, head.size) {
+		state = &State{
+			URL:          url,
+			ETag:         head.etag,
+			LastModified: head.lastModified,
+			TotalSize:    head.size,
+		}
+	}
+
+	file, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("resume: opening %s: %w", outputPath, err)
+	}
+	defer file.Close() // nolint
+
+	chunks := splitChunks(state.Missing(), int64(d.options.ChunkSizeMB)*1024*1024)
+	if err := d.fetchChunks(file, url, chunks, state, outputPath); err != nil {
+		return err
+	}
+
+	return Remove(outputPath)
+}
+
+type headInfo struct {
+	size         int64
+	etag         string
+	lastModified string
+}
+
+func (d *Downloader) head(url string) (headInfo, error) {
+	req, err := http.NewRequest(http.MethodHead, url, nil) // nolint:noctx
+	if err != nil {
+		return headInfo{}, err
+	}
+	if d.options.UserAgent != "" {
+		req.Header.Set("User-Agent", d.options.UserAgent)
+	}
+	if d.options.Refer != "" {
+		req.Header.Set("Referer", d.options.Refer)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return headInfo{}, err
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return headInfo{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength <= 0 {
+		// A server that can't report Content-Length (chunked responses, or an
+		// anti-hotlink/expired-link page served with a 2xx status) leaves
+		// State.Missing() unable to tell "nothing left to fetch" from "size
+		// unknown" — treat it as unresumable rather than risk writing a
+		// truncated file and deleting the sidecar as if it succeeded.
+		return headInfo{}, fmt.Errorf("server did not report a Content-Length for %s", url)
+	}
+
+	return headInfo{
+		size:         resp.ContentLength,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// splitChunks breaks ranges into pieces no larger than chunkSize, so each
+// journaled unit is small enough to cheaply re-fetch after a dropped
+// connection.
+func splitChunks(ranges []Range, chunkSize int64) []Range {
+	if chunkSize <= 0 {
+		return ranges
+	}
+	var chunks []Range
+	for _, r := range ranges {
+		for start := r.Start; start <= r.End; start += chunkSize {
+			end := start + chunkSize - 1
+			if end > r.End {
+				end = r.End
+			}
+			chunks = append(chunks, Range{Start: start, End: end})
+		}
+	}
+	return chunks
+}
+
+// fetchChunks downloads chunks into file at their respective offsets, up to
+// ThreadNumber at a time, journaling each completed chunk to state/outputPath
+// as it lands so a restart after a dropped connection only re-issues what's
+// still missing.
+func (d *Downloader) fetchChunks(file *os.File, url string, chunks []Range, state *State, outputPath string) error {
+	sem := make(chan struct{}, d.options.ThreadNumber)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	for _, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(chunk Range) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			data, err := d.fetchRange(url, chunk)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("resume: fetching range %d-%d: %w", chunk.Start, chunk.End, err)
+				}
+				mu.Unlock()
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if firstErr != nil {
+				return
+			}
+			if _, err := file.WriteAt(data, chunk.Start); err != nil {
+				firstErr = fmt.Errorf("resume: writing range %d-%d: %w", chunk.Start, chunk.End, err)
+				return
+			}
+			state.MarkDone(chunk.Start, chunk.End)
+			if err := state.Save(outputPath); err != nil {
+				firstErr = err
+				return
+			}
+			if d.options.OnProgress != nil {
+				d.options.OnProgress(state.BytesDone(), state.TotalSize)
+			}
+		}(chunk)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+func (d *Downloader) fetchRange(url string, r Range) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil) // nolint:noctx
+	if err != nil {
+		return nil, err
+	}
+	if d.options.UserAgent != "" {
+		req.Header.Set("User-Agent", d.options.UserAgent)
+	}
+	if d.options.Refer != "" {
+		req.Header.Set("Referer", d.options.Refer)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.Start, r.End))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusPartialContent {
+		// A 200 here means the server ignored our Range header and is about
+		// to send the whole file; writing that at this chunk's offset would
+		// corrupt the output, so only a true partial response is acceptable.
+		return nil, fmt.Errorf("server did not honor the range request (status %d)", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
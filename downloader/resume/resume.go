@@ -0,0 +1,144 @@
+// Package resume journals multi-thread download progress to a sidecar file
+// next to the output, so a dropped connection can re-issue only the byte
+// ranges that are still missing instead of restarting from zero.
+package resume
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Suffix is appended to the output path to name its sidecar file.
+const Suffix = ".lux-part"
+
+// Range is an inclusive byte range, [Start, End].
+type Range struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// State is the journaled state of one multi-thread download.
+type State struct {
+	URL          string  `json:"url"`
+	ETag         string  `json:"etag,omitempty"`
+	LastModified string  `json:"last_modified,omitempty"`
+	TotalSize    int64   `json:"total_size"`
+	StreamHash   string  `json:"stream_hash"`
+	Done         []Range `json:"done"`
+}
+
+// Path returns the sidecar path for output.
+func Path(output string) string {
+	return output + Suffix
+}
+
+// Load reads the sidecar for output. It returns (nil, nil) if no sidecar
+// exists yet, which is the normal case for a fresh download.
+func Load(output string) (*State, error) {
+	data, err := os.ReadFile(Path(output))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("resume: reading %s: %w", Path(output), err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("resume: parsing %s: %w", Path(output), err)
+	}
+	return &s, nil
+}
+
+// Matches reports whether s can still be used to resume a download of a
+// resource re-HEADed as url/etag/lastModified/totalSize, i.e. the resource
+// hasn't changed since the sidecar was written.
+func (s *State) Matches(url, etag, lastModified string, totalSize int64) bool {
+	if s.URL != url || s.TotalSize != totalSize {
+		return false
+	}
+	// An unchanged ETag or Last-Modified is sufficient; servers that send
+	// neither fall back on URL+size alone.
+	if etag != "" && s.ETag != "" {
+		return etag == s.ETag
+	}
+	if lastModified != "" && s.LastModified != "" {
+		return lastModified == s.LastModified
+	}
+	return true
+}
+
+// MarkDone records [start, end] as downloaded and coalesces it with any
+// adjacent or overlapping ranges already recorded, keeping the sidecar
+// compact instead of growing by one entry per chunk.
+func (s *State) MarkDone(start, end int64) {
+	s.Done = append(s.Done, Range{Start: start, End: end})
+
+	sort.Slice(s.Done, func(i, j int) bool {
+		return s.Done[i].Start < s.Done[j].Start
+	})
+
+	merged := s.Done[:0]
+	for _, r := range s.Done {
+		if len(merged) > 0 && r.Start <= merged[len(merged)-1].End+1 {
+			last := &merged[len(merged)-1]
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	s.Done = merged
+}
+
+// Missing returns the byte ranges not yet covered by Done, i.e. the ranges
+// that still need to be downloaded.
+func (s *State) Missing() []Range {
+	var missing []Range
+	var next int64
+	for _, r := range s.Done {
+		if r.Start > next {
+			missing = append(missing, Range{Start: next, End: r.Start - 1})
+		}
+		if r.End+1 > next {
+			next = r.End + 1
+		}
+	}
+	if next < s.TotalSize {
+		missing = append(missing, Range{Start: next, End: s.TotalSize - 1})
+	}
+	return missing
+}
+
+// BytesDone returns the total number of bytes covered by Done.
+func (s *State) BytesDone() int64 {
+	var done int64
+	for _, r := range s.Done {
+		done += r.End - r.Start + 1
+	}
+	return done
+}
+
+// Save writes s to output's sidecar file.
+func (s *State) Save(output string) error {
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return fmt.Errorf("resume: encoding: %w", err)
+	}
+	if err := os.WriteFile(Path(output), data, 0o644); err != nil {
+		return fmt.Errorf("resume: writing %s: %w", Path(output), err)
+	}
+	return nil
+}
+
+// Remove deletes output's sidecar file once the download completes
+// successfully. A missing file is not an error.
+func Remove(output string) error {
+	if err := os.Remove(Path(output)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("resume: removing %s: %w", Path(output), err)
+	}
+	return nil
+}
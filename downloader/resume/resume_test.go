@@ -0,0 +1,115 @@
+package resume
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStateMarkDoneCoalescesRanges(t *testing.T) {
+	s := &State{TotalSize: 100}
+
+	s.MarkDone(0, 9)
+	s.MarkDone(20, 29)
+	s.MarkDone(10, 19) // adjacent to both existing ranges, should merge them all
+
+	want := []Range{{Start: 0, End: 29}}
+	if !reflect.DeepEqual(s.Done, want) {
+		t.Errorf("Done = %+v, want %+v", s.Done, want)
+	}
+}
+
+func TestStateMarkDoneKeepsDisjointRanges(t *testing.T) {
+	s := &State{TotalSize: 100}
+
+	s.MarkDone(50, 59)
+	s.MarkDone(0, 9)
+
+	want := []Range{{Start: 0, End: 9}, {Start: 50, End: 59}}
+	if !reflect.DeepEqual(s.Done, want) {
+		t.Errorf("Done = %+v, want %+v", s.Done, want)
+	}
+}
+
+func TestStateMissing(t *testing.T) {
+	s := &State{TotalSize: 100}
+	s.MarkDone(0, 9)
+	s.MarkDone(50, 99)
+
+	want := []Range{{Start: 10, End: 49}}
+	if got := s.Missing(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Missing() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStateMissingFullyDoneIsEmpty(t *testing.T) {
+	s := &State{TotalSize: 10}
+	s.MarkDone(0, 9)
+
+	if got := s.Missing(); len(got) != 0 {
+		t.Errorf("Missing() = %+v, want empty", got)
+	}
+}
+
+func TestStateMatches(t *testing.T) {
+	s := &State{URL: "http://example.com/a.mp4", ETag: `"abc"`, TotalSize: 100}
+
+	if !s.Matches("http://example.com/a.mp4", `"abc"`, "", 100) {
+		t.Error("Matches() = false, want true for identical ETag/size")
+	}
+	if s.Matches("http://example.com/a.mp4", `"different"`, "", 100) {
+		t.Error("Matches() = true, want false when ETag changed")
+	}
+	if s.Matches("http://example.com/b.mp4", `"abc"`, "", 100) {
+		t.Error("Matches() = true, want false when URL changed")
+	}
+}
+
+func TestSaveAndLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "video.mp4")
+
+	s := &State{URL: "http://example.com/a.mp4", TotalSize: 100}
+	s.MarkDone(0, 49)
+	if err := s.Save(output); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	loaded, err := Load(output)
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if loaded == nil {
+		t.Fatal("Load() = nil, want a state")
+	}
+	if !reflect.DeepEqual(loaded.Done, s.Done) || loaded.TotalSize != s.TotalSize {
+		t.Errorf("Load() = %+v, want %+v", loaded, s)
+	}
+
+	if err := Remove(output); err != nil {
+		t.Fatalf("Remove() = %v", err)
+	}
+	if _, err := os.Stat(Path(output)); !os.IsNotExist(err) {
+		t.Errorf("sidecar still present after Remove(): %v", err)
+	}
+}
+
+func TestLoadMissingSidecarIsNotAnError(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Load(filepath.Join(dir, "nope.mp4"))
+	if err != nil {
+		t.Fatalf("Load() = %v, want nil error for a missing sidecar", err)
+	}
+	if s != nil {
+		t.Errorf("Load() = %+v, want nil", s)
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	got := splitChunks([]Range{{Start: 0, End: 24}}, 10)
+	want := []Range{{Start: 0, End: 9}, {Start: 10, End: 19}, {Start: 20, End: 24}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitChunks() = %+v, want %+v", got, want)
+	}
+}
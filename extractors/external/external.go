@@ -0,0 +1,168 @@
+// Package external bridges to a user-configured external tool (typically
+// yt-dlp) so that lux can cover sites it doesn't natively support without
+// leaving the lux CLI. It is registered as a fallback extractor: lux's own
+// site extractors are always tried first.
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iawia002/lux/extractors"
+	"github.com/iawia002/lux/internal/ratelimit"
+)
+
+// Options configures the external extractor.
+type Options struct {
+	// Binary is the path to the external tool, e.g. "yt-dlp".
+	Binary string
+	// Args are extra arguments inserted before the URL, e.g.
+	// []string{"--cookies", "cookies.txt"}.
+	Args []string
+	// Priority is stored but not read by this package: the Extractor does
+	// not order itself against other fallbacks. Lux's native extractors
+	// always run before it regardless of this value.
+	Priority int
+	// MinInterval rate-limits consecutive invocations, so a large playlist
+	// doesn't hammer the target with one process per item.
+	MinInterval time.Duration
+}
+
+// Extractor shells out to Options.Binary to extract a single URL.
+type Extractor struct {
+	options Options
+	limiter *ratelimit.Limiter
+}
+
+// New returns an Extractor configured with options. It panics if
+// options.Binary is empty, since registering a fallback with nothing to run
+// is a programmer error, not a runtime one.
+func New(options Options) *Extractor {
+	if options.Binary == "" {
+		panic("external: Options.Binary is required")
+	}
+	return &Extractor{
+		options: options,
+		limiter: ratelimit.New(options.MinInterval),
+	}
+}
+
+// ytDlpFormat mirrors the subset of yt-dlp's -J format entries lux needs.
+type ytDlpFormat struct {
+	FormatID string  `json:"format_id"`
+	URL      string  `json:"url"`
+	Ext      string  `json:"ext"`
+	Filesize int64   `json:"filesize"`
+	VCodec   string  `json:"vcodec"`
+	ACodec   string  `json:"acodec"`
+	Height   int     `json:"height"`
+	TBR      float64 `json:"tbr"`
+}
+
+// ytDlpSubtitle mirrors one entry of yt-dlp's per-language subtitle list.
+type ytDlpSubtitle struct {
+	URL string `json:"url"`
+	Ext string `json:"ext"`
+}
+
+// ytDlpInfo mirrors the subset of yt-dlp's JSON info dict lux needs.
+type ytDlpInfo struct {
+	ID         string                     `json:"id"`
+	Title      string                     `json:"title"`
+	Extractor  string                     `json:"extractor"`
+	WebpageURL string                     `json:"webpage_url"`
+	Timestamp  int64                      `json:"timestamp"`
+	Formats    []ytDlpFormat              `json:"formats"`
+	Subtitles  map[string][]ytDlpSubtitle `json:"subtitles"`
+}
+
+// Extract runs "<binary> <args...> -J <url>" and maps the resulting info
+// dict into extractors.Data so the rest of the lux pipeline (progress,
+// aria2 handoff, filename sanitization, caption download) keeps working.
+func (e *Extractor) Extract(url string) ([]*extractors.Data, error) {
+	ctx := context.Background()
+	if err := e.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	args := append(append([]string{}, e.options.Args...), "-J", url)
+	cmd := exec.CommandContext(ctx, e.options.Binary, args...) // nolint:gosec
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("external: running %s: %w", e.options.Binary, err)
+	}
+
+	var info ytDlpInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, fmt.Errorf("external: parsing %s output: %w", e.options.Binary, err)
+	}
+
+	return []*extractors.Data{toData(info)}, nil
+}
+
+func toData(info ytDlpInfo) *extractors.Data {
+	streams := make(map[string]*extractors.Stream, len(info.Formats))
+	for _, f := range info.Formats {
+		if f.URL == "" {
+			continue
+		}
+		streams[f.FormatID] = &extractors.Stream{
+			Parts: []*extractors.Part{
+				{URL: f.URL, Size: f.Filesize, Ext: f.Ext},
+			},
+			Size:    f.Filesize,
+			Quality: formatQuality(f),
+		}
+	}
+
+	return &extractors.Data{
+		Site:        info.Extractor,
+		Title:       info.Title,
+		Type:        extractors.DataTypeVideo,
+		URL:         info.WebpageURL,
+		SiteID:      info.ID,
+		PublishedAt: time.Unix(info.Timestamp, 0),
+		Streams:     streams,
+		Caption:     bestCaption(info.Subtitles),
+	}
+}
+
+// bestCaption picks a single caption track from yt-dlp's per-language
+// subtitle map to populate extractors.Data.Caption, so --caption keeps
+// working for videos resolved through the external extractor. It prefers
+// English when available, and otherwise falls back to whichever language
+// code sorts first, so the choice is at least deterministic rather than
+// depending on map iteration order.
+func bestCaption(subs map[string][]ytDlpSubtitle) *extractors.Caption {
+	if len(subs) == 0 {
+		return nil
+	}
+
+	lang := "en"
+	if _, ok := subs[lang]; !ok {
+		langs := make([]string, 0, len(subs))
+		for l := range subs {
+			langs = append(langs, l)
+		}
+		sort.Strings(langs)
+		lang = langs[0]
+	}
+
+	tracks := subs[lang]
+	if len(tracks) == 0 {
+		return nil
+	}
+	return &extractors.Caption{URL: tracks[0].URL, Ext: tracks[0].Ext}
+}
+
+func formatQuality(f ytDlpFormat) string {
+	if f.Height > 0 {
+		return fmt.Sprintf("%dp", f.Height)
+	}
+	return strings.TrimSpace(f.FormatID)
+}
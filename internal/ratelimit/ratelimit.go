@@ -0,0 +1,46 @@
+// Package ratelimit provides a small token-bucket limiter shared by code
+// paths that repeatedly hit the same external endpoint, e.g. invoking an
+// external extractor once per item in a large playlist.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter allows at most one event per interval.
+type Limiter struct {
+	interval time.Duration
+	tokens   chan struct{}
+}
+
+// New returns a Limiter that permits one event every interval. An interval
+// of zero disables limiting: Wait always returns immediately.
+func New(interval time.Duration) *Limiter {
+	l := &Limiter{
+		interval: interval,
+		tokens:   make(chan struct{}, 1),
+	}
+	if interval > 0 {
+		l.tokens <- struct{}{}
+	}
+	return l
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return nil
+	}
+
+	select {
+	case <-l.tokens:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	time.AfterFunc(l.interval, func() {
+		l.tokens <- struct{}{}
+	})
+	return nil
+}
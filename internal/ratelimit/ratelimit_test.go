@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewZeroIntervalNeverBlocks(t *testing.T) {
+	l := New(0)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatalf("Wait() = %v, want nil", err)
+		}
+	}
+}
+
+func TestWaitThrottlesAcrossCalls(t *testing.T) {
+	l := New(50 * time.Millisecond)
+	ctx := context.Background()
+
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("first Wait() = %v, want nil", err)
+	}
+
+	start := time.Now()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("second Wait() = %v, want nil", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("second Wait() returned after %v, want it to have blocked for roughly the configured interval", elapsed)
+	}
+}
+
+func TestWaitRespectsContextCancellation(t *testing.T) {
+	l := New(time.Hour)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait() = %v, want nil", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Error("second Wait() = nil, want context deadline exceeded")
+	}
+}
@@ -0,0 +1,505 @@
+// Package livestream records HLS (.m3u8) and MPEG-DASH (.mpd) manifests to
+// disk as they are broadcast, instead of downloading a single finished file.
+package livestream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Options controls how a live manifest is recorded.
+type Options struct {
+	// Quality is the user-requested live quality, matched against the
+	// manifest's variant streams (e.g. "best", "1080p").
+	Quality string
+	// MaxDuration stops the recording after the given duration has
+	// elapsed, even if the stream is still live. Zero means unlimited.
+	MaxDuration time.Duration
+	// Filename is a Go template evaluated against Info to build the
+	// output file name, mirroring the FC2 downloader's format string.
+	Filename string
+	// FFmpeg is the path to an ffmpeg binary used to remux the recorded
+	// segments on completion. Left empty, the raw concatenated segments
+	// are kept as-is.
+	FFmpeg string
+	// Concurrency bounds how many segments are fetched at once.
+	Concurrency int
+	OutputPath  string
+}
+
+// Info is the data made available to the Filename template.
+type Info struct {
+	Title string
+	Site  string
+}
+
+// IsManifest reports whether rawURL points at an HLS or DASH manifest,
+// the two live formats this package knows how to record.
+func IsManifest(rawURL string) bool {
+	u := strings.ToLower(strings.SplitN(rawURL, "?", 2)[0])
+	return strings.HasSuffix(u, ".m3u8") || strings.HasSuffix(u, ".mpd")
+}
+
+// Recorder polls a live manifest and writes its segments to disk.
+type Recorder struct {
+	options Options
+	client  *http.Client
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+// New returns a Recorder configured with options.
+func New(options Options) *Recorder {
+	if options.Concurrency <= 0 {
+		options.Concurrency = 5
+	}
+	return &Recorder{
+		options: options,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		seen:    make(map[string]struct{}),
+	}
+}
+
+// Record polls manifestURL on its target-duration interval until the stream
+// ends (#EXT-X-ENDLIST), MaxDuration elapses, or ctx is canceled. It returns
+// the path of the recorded part file(s).
+//
+// If manifestURL is an HLS master playlist, Record first resolves it to the
+// media playlist matching Options.Quality (DASH quality selection isn't
+// implemented; a .mpd URL is polled as given).
+func (r *Recorder) Record(ctx context.Context, manifestURL string, info Info) ([]string, error) {
+	name, err := r.filename(info)
+	if err != nil {
+		return nil, fmt.Errorf("livestream: rendering filename: %w", err)
+	}
+
+	manifestURL, err = r.resolveManifest(ctx, manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("livestream: resolving manifest: %w", err)
+	}
+
+	var cancel context.CancelFunc
+	if r.options.MaxDuration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.options.MaxDuration)
+		defer cancel()
+	}
+
+	part := 0
+	out, err := r.newPart(name, part)
+	if err != nil {
+		return nil, err
+	}
+	parts := []string{out.Name()}
+
+	lastSeq := -1
+	interval := 2 * time.Second
+	for {
+		playlist, err := r.fetchPlaylist(ctx, manifestURL)
+		if err != nil {
+			// Transient network failures resume from lastSeq on the next poll
+			// instead of aborting the recording outright.
+			select {
+			case <-ctx.Done():
+				out.Close() // nolint
+				return parts, ctx.Err()
+			case <-time.After(interval):
+				continue
+			}
+		}
+
+		if playlist.targetDuration > 0 {
+			interval = time.Duration(playlist.targetDuration) * time.Second
+		}
+
+		var newSegs []segment
+		for _, seg := range playlist.segments {
+			if seg.sequence > lastSeq {
+				newSegs = append(newSegs, seg)
+			}
+		}
+
+		if len(newSegs) > 0 {
+			fetched, fetchErrs := r.fetchSegments(ctx, newSegs)
+			for i, seg := range newSegs {
+				// A segment fetch failure (a transient 500, a dropped
+				// connection) mustn't abort the recording: stop writing at
+				// the first gap and let the next poll retry it, exactly like
+				// a fetchPlaylist failure above. Segments after the gap
+				// can't be written yet regardless of whether they already
+				// succeeded, since that would leave a hole in the part file.
+				if fetchErrs[i] != nil {
+					break
+				}
+				if seg.discontinuity {
+					out.Close() // nolint
+					part++
+					out, err = r.newPart(name, part)
+					if err != nil {
+						return parts, err
+					}
+					parts = append(parts, out.Name())
+				}
+				if fetched[i] != nil { // nil means this segment's URI was already seen
+					if _, err := out.Write(fetched[i]); err != nil {
+						return parts, fmt.Errorf("livestream: writing segment %s: %w", seg.uri, err)
+					}
+				}
+				lastSeq = seg.sequence
+			}
+		}
+
+		if playlist.ended {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			out.Close() // nolint
+			return parts, nil
+		case <-time.After(interval):
+		}
+	}
+	out.Close() // nolint
+
+	if r.options.FFmpeg != "" {
+		remuxed, err := r.remux(ctx, parts)
+		if err != nil {
+			return parts, fmt.Errorf("livestream: remux: %w", err)
+		}
+		return remuxed, nil
+	}
+	return parts, nil
+}
+
+func (r *Recorder) filename(info Info) (string, error) {
+	tmplText := r.options.Filename
+	if tmplText == "" {
+		tmplText = "{{.Site}}-{{.Title}}"
+	}
+	tmpl, err := template.New("live-filename").Parse(tmplText)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, info); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (r *Recorder) newPart(name string, part int) (*os.File, error) {
+	fileName := fmt.Sprintf("%s.part%d.ts", name, part)
+	if r.options.OutputPath != "" {
+		fileName = filepath.Join(r.options.OutputPath, fileName)
+	}
+	return os.Create(fileName)
+}
+
+// fetchSegments fetches segs concurrently, bounded by Options.Concurrency,
+// and returns their bodies and any per-segment error, both index-aligned
+// with segs. Errors are reported per segment rather than failing the whole
+// batch, so the caller can write whatever landed before the first failure
+// and retry the rest on the next poll instead of aborting the recording.
+func (r *Recorder) fetchSegments(ctx context.Context, segs []segment) ([][]byte, []error) {
+	data := make([][]byte, len(segs))
+	errs := make([]error, len(segs))
+
+	sem := make(chan struct{}, r.options.Concurrency)
+	var wg sync.WaitGroup
+	for i, seg := range segs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, uri string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			data[i], errs[i] = r.fetchSegment(ctx, uri)
+		}(i, seg.uri)
+	}
+	wg.Wait()
+
+	return data, errs
+}
+
+// fetchSegment downloads uri's body, or returns a nil slice without error if
+// uri was already fetched on an earlier poll (the HLS media sequence can
+// repeat segments across polls near the live edge). The URI is only recorded
+// as seen once its body has actually been read, so a failed fetch (a
+// transient 500, a dropped connection) is retried on the next poll instead
+// of being skipped forever.
+func (r *Recorder) fetchSegment(ctx context.Context, uri string) ([]byte, error) {
+	r.mu.Lock()
+	_, alreadySeen := r.seen[uri]
+	r.mu.Unlock()
+	if alreadySeen {
+		return nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.seen[uri] = struct{}{}
+	r.mu.Unlock()
+	return body, nil
+}
+
+type segment struct {
+	uri           string
+	sequence      int
+	discontinuity bool
+}
+
+type playlist struct {
+	targetDuration int
+	segments       []segment
+	ended          bool
+}
+
+func (r *Recorder) fetchPlaylist(ctx context.Context, manifestURL string) (*playlist, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseM3U8(string(body), manifestURL)
+}
+
+func parseM3U8(body, baseURL string) (*playlist, error) {
+	p := &playlist{}
+	seq := 0
+	discontinuity := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			d, err := strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"))
+			if err == nil {
+				p.targetDuration = d
+			}
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			s, err := strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"))
+			if err == nil {
+				seq = s
+			}
+		case line == "#EXT-X-DISCONTINUITY":
+			discontinuity = true
+		case line == "#EXT-X-ENDLIST":
+			p.ended = true
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		default:
+			p.segments = append(p.segments, segment{
+				uri:           resolveURI(baseURL, line),
+				sequence:      seq,
+				discontinuity: discontinuity,
+			})
+			seq++
+			discontinuity = false
+		}
+	}
+	return p, nil
+}
+
+// resolveManifest fetches manifestURL and, if it is an HLS master playlist,
+// returns the media playlist URL of the variant matching Options.Quality.
+// Any other manifest (a media playlist, or a .mpd) is returned unchanged —
+// DASH representation selection isn't implemented.
+func (r *Recorder) resolveManifest(ctx context.Context, manifestURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	text := string(body)
+	if !isMasterPlaylist(text) {
+		return manifestURL, nil
+	}
+	variants := parseMasterPlaylist(text, manifestURL)
+	if chosen := selectVariant(variants, r.options.Quality); chosen != "" {
+		return chosen, nil
+	}
+	return manifestURL, nil
+}
+
+// variant is one #EXT-X-STREAM-INF entry of an HLS master playlist.
+type variant struct {
+	url        string
+	bandwidth  int
+	resolution string
+}
+
+func isMasterPlaylist(body string) bool {
+	return strings.Contains(body, "#EXT-X-STREAM-INF:")
+}
+
+func parseMasterPlaylist(body, baseURL string) []variant {
+	var variants []variant
+	lines := strings.Split(body, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "#EXT-X-STREAM-INF:") {
+			continue
+		}
+
+		v := variant{}
+		for _, attr := range splitAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:")) {
+			kv := strings.SplitN(attr, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "BANDWIDTH":
+				if n, err := strconv.Atoi(kv[1]); err == nil {
+					v.bandwidth = n
+				}
+			case "RESOLUTION":
+				v.resolution = kv[1]
+			}
+		}
+
+		// The URI line is the next non-blank line; if that line is a tag
+		// instead, the entry is skipped.
+		for j := i + 1; j < len(lines); j++ {
+			next := strings.TrimSpace(lines[j])
+			if next == "" {
+				continue
+			}
+			if !strings.HasPrefix(next, "#") {
+				v.url = resolveURI(baseURL, next)
+				variants = append(variants, v)
+				i = j
+			}
+			break
+		}
+	}
+	return variants
+}
+
+// splitAttributes splits an #EXT-X-STREAM-INF attribute list on commas,
+// ignoring commas inside quoted values (e.g. CODECS="avc1.4d401f,mp4a.40.2").
+func splitAttributes(s string) []string {
+	var attrs []string
+	var cur strings.Builder
+	inQuotes := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			cur.WriteRune(r)
+		case r == ',' && !inQuotes:
+			attrs = append(attrs, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		attrs = append(attrs, cur.String())
+	}
+	return attrs
+}
+
+// selectVariant picks the variant matching quality: "best" or "" picks the
+// highest bandwidth, anything else is matched against each variant's
+// resolution (e.g. "1080p" against a "1920x1080" RESOLUTION) or exact
+// bandwidth, falling back to "best" if nothing matches.
+func selectVariant(variants []variant, quality string) string {
+	if len(variants) == 0 {
+		return ""
+	}
+	if quality != "" && quality != "best" {
+		want := strings.ToLower(strings.TrimSuffix(quality, "p"))
+		for _, v := range variants {
+			height := v.resolution
+			if idx := strings.IndexByte(v.resolution, 'x'); idx != -1 {
+				height = v.resolution[idx+1:]
+			}
+			if strings.ToLower(height) == want || strconv.Itoa(v.bandwidth) == quality {
+				return v.url
+			}
+		}
+	}
+	best := variants[0]
+	for _, v := range variants[1:] {
+		if v.bandwidth > best.bandwidth {
+			best = v
+		}
+	}
+	return best.url
+}
+
+func resolveURI(baseURL, uri string) string {
+	if strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
+		return uri
+	}
+	idx := strings.LastIndex(baseURL, "/")
+	if idx == -1 {
+		return uri
+	}
+	return baseURL[:idx+1] + uri
+}
+
+func (r *Recorder) remux(ctx context.Context, parts []string) ([]string, error) {
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		dst := strings.TrimSuffix(p, filepath.Ext(p)) + ".mp4"
+		cmd := exec.CommandContext(ctx, r.options.FFmpeg, "-y", "-i", p, "-c", "copy", dst) // nolint:gosec
+		if err := cmd.Run(); err != nil {
+			return out, fmt.Errorf("ffmpeg remux of %s failed: %w", p, err)
+		}
+		out = append(out, dst)
+	}
+	return out, nil
+}
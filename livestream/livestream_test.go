@@ -0,0 +1,111 @@
+package livestream
+
+import "testing"
+
+func TestParseM3U8(t *testing.T) {
+	body := `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:100
+#EXTINF:6.0,
+seg100.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:6.0,
+seg101.ts
+`
+	p, err := parseM3U8(body, "http://example.com/live/index.m3u8")
+	if err != nil {
+		t.Fatalf("parseM3U8() = %v", err)
+	}
+	if p.targetDuration != 6 {
+		t.Errorf("targetDuration = %d, want 6", p.targetDuration)
+	}
+	if p.ended {
+		t.Error("ended = true, want false (no #EXT-X-ENDLIST)")
+	}
+	if len(p.segments) != 2 {
+		t.Fatalf("got %d segments, want 2", len(p.segments))
+	}
+	if p.segments[0].sequence != 100 || p.segments[0].uri != "http://example.com/live/seg100.ts" {
+		t.Errorf("segments[0] = %+v", p.segments[0])
+	}
+	if !p.segments[1].discontinuity || p.segments[1].sequence != 101 {
+		t.Errorf("segments[1] = %+v, want discontinuity at sequence 101", p.segments[1])
+	}
+}
+
+func TestParseM3U8Ended(t *testing.T) {
+	body := "#EXTM3U\n#EXTINF:6.0,\nseg0.ts\n#EXT-X-ENDLIST\n"
+	p, err := parseM3U8(body, "http://example.com/index.m3u8")
+	if err != nil {
+		t.Fatalf("parseM3U8() = %v", err)
+	}
+	if !p.ended {
+		t.Error("ended = false, want true")
+	}
+}
+
+func TestResolveURI(t *testing.T) {
+	cases := []struct{ base, uri, want string }{
+		{"http://example.com/live/index.m3u8", "seg0.ts", "http://example.com/live/seg0.ts"},
+		{"http://example.com/live/index.m3u8", "http://cdn.example.com/seg0.ts", "http://cdn.example.com/seg0.ts"},
+		{"noSlashHere", "seg0.ts", "seg0.ts"},
+	}
+	for _, c := range cases {
+		if got := resolveURI(c.base, c.uri); got != c.want {
+			t.Errorf("resolveURI(%q, %q) = %q, want %q", c.base, c.uri, got, c.want)
+		}
+	}
+}
+
+func TestIsMasterPlaylist(t *testing.T) {
+	if isMasterPlaylist("#EXTM3U\n#EXTINF:6.0,\nseg0.ts\n") {
+		t.Error("media playlist misdetected as master")
+	}
+	if !isMasterPlaylist("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nlow.m3u8\n") {
+		t.Error("master playlist not detected")
+	}
+}
+
+func TestParseMasterPlaylist(t *testing.T) {
+	body := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360
+low/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2800000,RESOLUTION=1920x1080
+high/index.m3u8
+`
+	variants := parseMasterPlaylist(body, "http://example.com/live/index.m3u8")
+	if len(variants) != 2 {
+		t.Fatalf("got %d variants, want 2", len(variants))
+	}
+	if variants[0].bandwidth != 800000 || variants[0].resolution != "640x360" {
+		t.Errorf("variants[0] = %+v", variants[0])
+	}
+	if variants[0].url != "http://example.com/live/low/index.m3u8" {
+		t.Errorf("variants[0].url = %q", variants[0].url)
+	}
+	if variants[1].bandwidth != 2800000 {
+		t.Errorf("variants[1].bandwidth = %d, want 2800000", variants[1].bandwidth)
+	}
+}
+
+func TestSelectVariant(t *testing.T) {
+	variants := []variant{
+		{url: "low", bandwidth: 800000, resolution: "640x360"},
+		{url: "high", bandwidth: 2800000, resolution: "1920x1080"},
+	}
+	if got := selectVariant(variants, ""); got != "high" {
+		t.Errorf("selectVariant(%q) = %q, want highest-bandwidth variant", "", got)
+	}
+	if got := selectVariant(variants, "best"); got != "high" {
+		t.Errorf("selectVariant(best) = %q, want high", got)
+	}
+	if got := selectVariant(variants, "360p"); got != "low" {
+		t.Errorf("selectVariant(360p) = %q, want low", got)
+	}
+	if got := selectVariant(variants, "unknown-quality"); got != "high" {
+		t.Errorf("selectVariant(unknown) = %q, want fallback to best (high)", got)
+	}
+	if got := selectVariant(nil, "best"); got != "" {
+		t.Errorf("selectVariant(nil) = %q, want empty", got)
+	}
+}
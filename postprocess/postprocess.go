@@ -0,0 +1,282 @@
+// Package postprocess runs ffmpeg over an already-downloaded item to merge
+// separate audio/video parts, recode to a different container, and embed
+// thumbnails, subtitles and metadata — the steps lux used to leave to the
+// user's own ffmpeg invocation.
+package postprocess
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/iawia002/lux/extractors"
+)
+
+// Options controls which post-processing steps run and how.
+type Options struct {
+	// FFmpegPath is the ffmpeg binary to invoke. Defaults to "ffmpeg" on
+	// PATH when empty.
+	FFmpegPath string
+	// MergeOutputFormat is the container used when muxing separate audio
+	// and video streams, e.g. "mp4", "mkv", "webm".
+	MergeOutputFormat string
+	// Recode re-encodes the final file into this container/codec set
+	// (currently "mp4" or "mkv") even when no merge was needed.
+	Recode string
+	EmbedThumbnail bool
+	EmbedSubs      bool
+	BurnSubs       bool
+	EmbedMetadata  bool
+	// WriteInfoJSON writes a ".info.json" sidecar with the extracted data,
+	// for archival.
+	WriteInfoJSON bool
+	// KeepParts leaves the original, pre-merge part files on disk instead
+	// of removing them once the pipeline succeeds.
+	KeepParts bool
+}
+
+// Processor runs the configured post-processing steps for one item.
+type Processor struct {
+	options Options
+}
+
+// New returns a Processor configured with options.
+func New(options Options) *Processor {
+	if options.FFmpegPath == "" {
+		options.FFmpegPath = "ffmpeg"
+	}
+	return &Processor{options: options}
+}
+
+// Run post-processes item, whose streams were downloaded to partPaths
+// (one path per extractors.Part, in stream order) producing outputPath.
+// It fails gracefully with a clear message when ffmpeg is required but
+// missing, rather than a raw "executable file not found" error.
+func (p *Processor) Run(ctx context.Context, item *extractors.Data, partPaths []string, outputPath string) (string, error) {
+	if p.needsFFmpeg() {
+		if _, err := exec.LookPath(p.options.FFmpegPath); err != nil {
+			return "", fmt.Errorf("postprocess: ffmpeg not found (tried %q); install ffmpeg or pass --ffmpeg-location", p.options.FFmpegPath)
+		}
+	}
+
+	current := partPaths
+	out := outputPath
+
+	if len(current) > 1 {
+		merged, err := p.merge(ctx, current, out)
+		if err != nil {
+			return "", fmt.Errorf("postprocess: merging streams: %w", err)
+		}
+		out = merged
+		current = []string{merged}
+	}
+
+	if p.options.Recode != "" {
+		recoded, err := p.recode(ctx, out)
+		if err != nil {
+			return "", fmt.Errorf("postprocess: recoding to %s: %w", p.options.Recode, err)
+		}
+		out = recoded
+	}
+
+	if p.options.EmbedThumbnail {
+		if thumb := thumbnailPath(out); thumb != "" {
+			embedded, err := p.embedThumbnail(ctx, out, thumb)
+			if err != nil {
+				return "", fmt.Errorf("postprocess: embedding thumbnail: %w", err)
+			}
+			out = embedded
+		}
+	}
+
+	if (p.options.EmbedSubs || p.options.BurnSubs) && item.Caption != nil {
+		embedded, err := p.embedSubs(ctx, out, item.Caption.URL)
+		if err != nil {
+			return "", fmt.Errorf("postprocess: embedding subtitles: %w", err)
+		}
+		out = embedded
+	}
+
+	if p.options.EmbedMetadata {
+		if err := p.embedMetadata(ctx, out, item); err != nil {
+			return "", fmt.Errorf("postprocess: embedding metadata: %w", err)
+		}
+	}
+
+	if p.options.WriteInfoJSON {
+		if err := writeInfoJSON(out, item); err != nil {
+			return "", fmt.Errorf("postprocess: writing info json: %w", err)
+		}
+	}
+
+	if !p.options.KeepParts {
+		for _, part := range partPaths {
+			if part != out {
+				os.Remove(part) // nolint:errcheck
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func (p *Processor) needsFFmpeg() bool {
+	return p.options.MergeOutputFormat != "" || p.options.Recode != "" ||
+		p.options.EmbedThumbnail || p.options.EmbedSubs || p.options.BurnSubs || p.options.EmbedMetadata
+}
+
+// merge muxes the audio and video parts together, copying codecs when
+// possible so no re-encode is needed, and re-encoding with the container's
+// standard codecs when the stream copy fails.
+func (p *Processor) merge(ctx context.Context, parts []string, outputPath string) (string, error) {
+	format := p.options.MergeOutputFormat
+	if format == "" {
+		format = "mp4"
+	}
+	dst := withExt(outputPath, format)
+
+	args := []string{"-y"}
+	for _, part := range parts {
+		args = append(args, "-i", part)
+	}
+	copyArgs := append(append([]string{}, args...), "-c", "copy", dst)
+	if err := p.run(ctx, copyArgs); err == nil {
+		return dst, nil
+	}
+
+	switch strings.ToLower(format) {
+	case "webm":
+		args = append(args, "-c:v", "libvpx-vp9", "-c:a", "libopus")
+	default: // mp4, mkv
+		args = append(args, "-c:v", "libx264", "-c:a", "aac")
+	}
+	args = append(args, dst)
+
+	if err := p.run(ctx, args); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// recode re-encodes src into the requested container using that container's
+// standard codecs, since a stream copy (-c copy) can't convert between
+// incompatible codecs — exactly the case --recode exists for (e.g. a VP9/Opus
+// webm into an mp4, which requires H.264/AAC).
+func (p *Processor) recode(ctx context.Context, src string) (string, error) {
+	dst := withExt(src, p.options.Recode)
+	if dst == src {
+		return src, nil
+	}
+
+	args := []string{"-y", "-i", src}
+	switch strings.ToLower(p.options.Recode) {
+	case "webm":
+		args = append(args, "-c:v", "libvpx-vp9", "-c:a", "libopus")
+	default: // mp4, mkv
+		args = append(args, "-c:v", "libx264", "-c:a", "aac")
+	}
+	args = append(args, dst)
+
+	if err := p.run(ctx, args); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// thumbnailPath looks for a thumbnail saved alongside src by the downloader
+// (as "<base>.jpg"); it returns "" if none is present.
+func thumbnailPath(src string) string {
+	path := strings.TrimSuffix(src, filepath.Ext(src)) + ".jpg"
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+func (p *Processor) embedThumbnail(ctx context.Context, src, thumbPath string) (string, error) {
+	dst := addSuffix(src, ".thumb")
+	if err := p.run(ctx, []string{
+		"-y", "-i", src, "-i", thumbPath,
+		"-map", "0", "-map", "1", "-c", "copy", "-disposition:v:1", "attached_pic", dst,
+	}); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+func (p *Processor) embedSubs(ctx context.Context, src, subsURL string) (string, error) {
+	dst := addSuffix(src, ".subbed")
+	if p.options.BurnSubs {
+		filter := fmt.Sprintf("subtitles=%s", subsURL)
+		if err := p.run(ctx, []string{"-y", "-i", src, "-vf", filter, dst}); err != nil {
+			return "", err
+		}
+		return dst, nil
+	}
+
+	if err := p.run(ctx, []string{
+		"-y", "-i", src, "-i", subsURL,
+		"-c", "copy", "-c:s", "mov_text", dst,
+	}); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// embedMetadata writes an ffmetadata sidecar with the title, the site name
+// (as artist) and the source URL, and remuxes it into the file, preserving
+// all streams unchanged. No uploader is written.
+func (p *Processor) embedMetadata(ctx context.Context, src string, item *extractors.Data) error {
+	metaPath := src + ".ffmetadata"
+	content := fmt.Sprintf(";FFMETADATA1\ntitle=%s\nartist=%s\ncomment=%s\n",
+		escapeMetadata(item.Title), escapeMetadata(item.Site), escapeMetadata(item.URL))
+	if err := os.WriteFile(metaPath, []byte(content), 0o644); err != nil {
+		return err
+	}
+	defer os.Remove(metaPath) // nolint:errcheck
+
+	tmp := addSuffix(src, ".meta")
+	if err := p.run(ctx, []string{
+		"-y", "-i", src, "-i", metaPath, "-map_metadata", "1",
+		"-c", "copy", tmp,
+	}); err != nil {
+		return err
+	}
+	return os.Rename(tmp, src)
+}
+
+func (p *Processor) run(ctx context.Context, args []string) error {
+	cmd := exec.CommandContext(ctx, p.options.FFmpegPath, args...) // nolint:gosec
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func withExt(path, ext string) string {
+	ext = strings.TrimPrefix(ext, ".")
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "." + ext
+}
+
+func addSuffix(path, suffix string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + suffix + ext
+}
+
+func escapeMetadata(s string) string {
+	replacer := strings.NewReplacer("=", "\\=", ";", "\\;", "#", "\\#", "\n", "\\\n")
+	return replacer.Replace(s)
+}
+
+func writeInfoJSON(outputPath string, item *extractors.Data) error {
+	path := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".info.json"
+	data, err := json.MarshalIndent(item, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,76 @@
+package postprocess
+
+import "testing"
+
+func TestWithExt(t *testing.T) {
+	cases := []struct {
+		path string
+		ext  string
+		want string
+	}{
+		{"video.flv", "mp4", "video.mp4"},
+		{"video.flv", ".mkv", "video.mkv"},
+		{"/tmp/out/video.flv", "webm", "/tmp/out/video.webm"},
+		{"video", "mp4", "video.mp4"},
+	}
+	for _, c := range cases {
+		if got := withExt(c.path, c.ext); got != c.want {
+			t.Errorf("withExt(%q, %q) = %q, want %q", c.path, c.ext, got, c.want)
+		}
+	}
+}
+
+func TestAddSuffix(t *testing.T) {
+	cases := []struct {
+		path   string
+		suffix string
+		want   string
+	}{
+		{"video.mp4", ".subbed", "video.subbed.mp4"},
+		{"/tmp/out/video.mp4", ".thumb", "/tmp/out/video.thumb.mp4"},
+	}
+	for _, c := range cases {
+		if got := addSuffix(c.path, c.suffix); got != c.want {
+			t.Errorf("addSuffix(%q, %q) = %q, want %q", c.path, c.suffix, got, c.want)
+		}
+	}
+}
+
+func TestEscapeMetadata(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"plain title", "plain title"},
+		{"a=b", "a\\=b"},
+		{"a;b#c", "a\\;b\\#c"},
+		{"line1\nline2", "line1\\\nline2"},
+	}
+	for _, c := range cases {
+		if got := escapeMetadata(c.in); got != c.want {
+			t.Errorf("escapeMetadata(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestNeedsFFmpeg(t *testing.T) {
+	cases := []struct {
+		name    string
+		options Options
+		want    bool
+	}{
+		{"nothing set", Options{}, false},
+		{"merge", Options{MergeOutputFormat: "mp4"}, true},
+		{"recode", Options{Recode: "mkv"}, true},
+		{"embed thumbnail", Options{EmbedThumbnail: true}, true},
+		{"embed subs", Options{EmbedSubs: true}, true},
+		{"embed metadata", Options{EmbedMetadata: true}, true},
+		{"write info json only", Options{WriteInfoJSON: true}, false},
+	}
+	for _, c := range cases {
+		p := New(c.options)
+		if got := p.needsFFmpeg(); got != c.want {
+			t.Errorf("%s: needsFFmpeg() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package progress reports download progress events from the concurrent
+// download scheduler to the user, in one of a few interchangeable formats.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Stage identifies where in the pipeline an Event was emitted.
+type Stage string
+
+const (
+	StageExtracting  Stage = "extracting"
+	StageDownloading Stage = "downloading"
+	StageDone        Stage = "done"
+	StageError       Stage = "error"
+)
+
+// Event is one progress update for a single URL's download.
+type Event struct {
+	URL    string        `json:"url"`
+	Stage  Stage         `json:"stage"`
+	Format string        `json:"format,omitempty"`
+	Bytes  int64         `json:"bytes"`
+	Total  int64         `json:"total"`
+	ETA    time.Duration `json:"eta,omitempty"`
+	Err    string        `json:"error,omitempty"`
+}
+
+// Reporter consumes Events from any number of concurrent workers. All
+// implementations must be safe for concurrent use.
+type Reporter interface {
+	Report(Event)
+	// Close flushes and tears down the reporter once every worker is done.
+	Close()
+}
+
+// New returns the Reporter for kind: "bar", "json", "plain" or "tui". An
+// unknown kind falls back to "plain", matching lux's existing behavior of
+// printing plain progress to stdout.
+func New(kind string, out io.Writer) Reporter {
+	switch kind {
+	case "json":
+		return &jsonReporter{out: out, enc: json.NewEncoder(out)}
+	case "bar":
+		return newLiveReporter(out, false)
+	case "tui":
+		return newLiveReporter(out, true)
+	default:
+		return &plainReporter{out: out}
+	}
+}
+
+type plainReporter struct {
+	out io.Writer
+	mu  sync.Mutex
+}
+
+func (p *plainReporter) Report(e Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch e.Stage {
+	case StageError:
+		fmt.Fprintf(p.out, "%s: %s\n", color.CyanString(e.URL), e.Err)
+	case StageDone:
+		fmt.Fprintf(p.out, "%s: done\n", color.CyanString(e.URL))
+	default:
+		if e.Total > 0 {
+			fmt.Fprintf(p.out, "%s: %d/%d bytes\n", color.CyanString(e.URL), e.Bytes, e.Total)
+		}
+	}
+}
+
+func (p *plainReporter) Close() {}
+
+type jsonReporter struct {
+	out io.Writer
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (j *jsonReporter) Report(e Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.enc.Encode(e) // nolint:errcheck
+}
+
+func (j *jsonReporter) Close() {}
+
+// barWidth is the number of "=" characters a fully complete bar draws.
+const barWidth = 30
+
+// liveReporter redraws one progress bar per reported download in place, using
+// ANSI cursor movement — lux's own dependency-free stand-in for a terminal
+// multi-bar library. In multiBar mode ("tui") it draws one line per URL plus
+// a trailing aggregate throughput line; finished and failed URLs keep their
+// line, marked with their final stage, and are not removed from the view.
+// Otherwise ("bar") it draws a single aggregate line for the whole run.
+type liveReporter struct {
+	out      io.Writer
+	multiBar bool
+
+	mu        sync.Mutex
+	order     []string
+	lines     map[string]*lineState
+	drawnRows int
+}
+
+type lineState struct {
+	bytes int64
+	total int64
+	stage Stage
+}
+
+func newLiveReporter(out io.Writer, multiBar bool) *liveReporter {
+	return &liveReporter{
+		out:      out,
+		multiBar: multiBar,
+		lines:    make(map[string]*lineState),
+	}
+}
+
+func (l *liveReporter) Report(e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line, ok := l.lines[e.URL]
+	if !ok {
+		line = &lineState{}
+		l.lines[e.URL] = line
+		l.order = append(l.order, e.URL)
+	}
+	line.stage = e.Stage
+	if e.Bytes > 0 {
+		line.bytes = e.Bytes
+	}
+	if e.Total > 0 {
+		line.total = e.Total
+	}
+
+	l.render()
+}
+
+func (l *liveReporter) render() {
+	rows := l.rows()
+
+	if l.drawnRows > 0 {
+		fmt.Fprintf(l.out, "\x1b[%dA", l.drawnRows)
+	}
+	for _, row := range rows {
+		fmt.Fprintf(l.out, "\r\x1b[K%s\n", row)
+	}
+	l.drawnRows = len(rows)
+}
+
+func (l *liveReporter) rows() []string {
+	if !l.multiBar {
+		var bytes, total int64
+		active := 0
+		for _, line := range l.lines {
+			bytes += line.bytes
+			total += line.total
+			if line.stage != StageDone && line.stage != StageError {
+				active++
+			}
+		}
+		return []string{fmt.Sprintf("%s  %s", bar(bytes, total), color.CyanString("%d active", active))}
+	}
+
+	rows := make([]string, 0, len(l.order)+1)
+	var bytes, total int64
+	for _, url := range l.order {
+		line := l.lines[url]
+		bytes += line.bytes
+		total += line.total
+		rows = append(rows, fmt.Sprintf("%s %s [%s]", bar(line.bytes, line.total), color.CyanString(url), line.stage))
+	}
+	rows = append(rows, fmt.Sprintf("total %s", bar(bytes, total)))
+	return rows
+}
+
+func bar(bytes, total int64) string {
+	filled := 0
+	if total > 0 {
+		filled = int(float64(barWidth) * float64(bytes) / float64(total))
+		if filled > barWidth {
+			filled = barWidth
+		}
+	}
+	return fmt.Sprintf("[%s%s] %d/%d", strings.Repeat("=", filled), strings.Repeat(" ", barWidth-filled), bytes, total)
+}
+
+func (l *liveReporter) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintln(l.out)
+}
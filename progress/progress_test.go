@@ -0,0 +1,81 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBar(t *testing.T) {
+	cases := []struct {
+		bytes, total int64
+		wantFilled   int
+	}{
+		{0, 100, 0},
+		{50, 100, barWidth / 2},
+		{100, 100, barWidth},
+		{150, 100, barWidth}, // clamps, doesn't overflow the bar
+		{10, 0, 0},           // unknown total: no crash, no fill
+	}
+	for _, c := range cases {
+		got := bar(c.bytes, c.total)
+		if filled := strings.Count(got, "="); filled != c.wantFilled {
+			t.Errorf("bar(%d, %d) = %q, filled %d chars, want %d", c.bytes, c.total, got, filled, c.wantFilled)
+		}
+	}
+}
+
+func TestPlainReporterWritesOneLinePerEvent(t *testing.T) {
+	var buf bytes.Buffer
+	r := New("plain", &buf)
+
+	r.Report(Event{URL: "http://example.com/a", Stage: StageDownloading, Bytes: 50, Total: 100})
+	r.Report(Event{URL: "http://example.com/a", Stage: StageDone})
+	r.Close()
+
+	out := buf.String()
+	if !strings.Contains(out, "50/100 bytes") {
+		t.Errorf("output = %q, want a bytes progress line", out)
+	}
+	if !strings.Contains(out, "done") {
+		t.Errorf("output = %q, want a done line", out)
+	}
+}
+
+func TestJSONReporterEncodesOneEventPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	r := New("json", &buf)
+
+	r.Report(Event{URL: "http://example.com/a", Stage: StageDone})
+	r.Report(Event{URL: "http://example.com/b", Stage: StageError, Err: "boom"})
+	r.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatalf("unmarshal second line: %v", err)
+	}
+	if e.URL != "http://example.com/b" || e.Err != "boom" {
+		t.Errorf("decoded event = %+v, want URL b / Err boom", e)
+	}
+}
+
+func TestLiveReporterTracksActiveDownloads(t *testing.T) {
+	var buf bytes.Buffer
+	r := newLiveReporter(&buf, true)
+
+	r.Report(Event{URL: "a", Stage: StageDownloading, Bytes: 10, Total: 100})
+	r.Report(Event{URL: "b", Stage: StageDownloading, Bytes: 20, Total: 100})
+
+	if len(r.order) != 2 {
+		t.Fatalf("order = %v, want 2 tracked URLs", r.order)
+	}
+	rows := r.rows()
+	if len(rows) != 3 { // one row per URL plus the aggregate row
+		t.Fatalf("rows() returned %d rows, want 3: %v", len(rows), rows)
+	}
+}